@@ -0,0 +1,258 @@
+package blocks
+
+import (
+	"github.com/epfl-dcsl/schedsim/blocks/prque"
+	"github.com/epfl-dcsl/schedsim/engine"
+)
+
+// ReqClass identifies a category of request for the purposes of cost
+// accounting; it is independent of ServiceTime so operators can price
+// classes differently from how long they actually take to run.
+type ReqClass int
+
+// ClientReq is a Request tagged with the client it belongs to, so a
+// CostTracker can attribute cost and enforce per-client bandwidth limits.
+type ClientReq struct {
+	Request
+	client ClientID
+	class  ReqClass
+}
+
+// NewClientReq returns a new *ClientReq for client in class, with the
+// given service time.
+func NewClientReq(serviceTime float64, client ClientID, class ReqClass) *ClientReq {
+	return &ClientReq{Request: Request{ServiceTime: serviceTime}, client: client, class: class}
+}
+
+// ClientID identifies the client a request belongs to, for bandwidth
+// accounting in CostTracker.
+type ClientID int
+
+// priorityTier is a capacity class a client can be pinned to; VIP clients
+// draw from a reserved share of the token-bucket refill rate before any
+// remainder is shared fairly among best-effort clients.
+type priorityTier int
+
+const (
+	// TierBestEffort clients share whatever bandwidth VIP tiers don't claim.
+	TierBestEffort priorityTier = iota
+	// TierVIP clients draw from their reserved share first.
+	TierVIP
+)
+
+// clientBucket is a client's token bucket: tokens accrue at rate per unit
+// time up to capacity, and every accepted request spends its class's cost.
+// rate is not set directly by the caller — it is derived from the
+// client's tier by CostTracker.recomputeRates whenever tier membership
+// changes.
+type clientBucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	tier     priorityTier
+	lastTime float64
+}
+
+func (b *clientBucket) refill(now float64) {
+	elapsed := now - b.lastTime
+	b.lastTime = now
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// CostTracker assigns each request class a benchmarked cost, independent
+// of ServiceTime, and enforces per-client token-bucket bandwidth limits
+// before a request is admitted. totalRate is the aggregate refill rate
+// available across all clients; vipShare is the fraction of totalRate
+// reserved for TierVIP clients, split evenly among however many of them
+// are currently registered. Whatever totalRate isn't reserved for VIP is
+// split evenly, in turn, among TierBestEffort clients.
+type CostTracker struct {
+	costTable  map[ReqClass]float64
+	correction float64
+	clients    map[ClientID]*clientBucket
+	totalRate  float64
+	vipShare   float64
+}
+
+// NewCostTracker returns a new *CostTracker using costTable (benchmarked,
+// pre-correction costs per class), a global correction factor applied to
+// every lookup, an aggregate refill rate totalRate, and vipShare (in
+// [0, 1]) reserved for TierVIP clients.
+func NewCostTracker(costTable map[ReqClass]float64, correction, totalRate, vipShare float64) *CostTracker {
+	return &CostTracker{
+		costTable:  costTable,
+		correction: correction,
+		clients:    make(map[ClientID]*clientBucket),
+		totalRate:  totalRate,
+		vipShare:   vipShare,
+	}
+}
+
+// Cost returns the corrected cost of admitting one request of class.
+func (t *CostTracker) Cost(class ReqClass) float64 {
+	return t.costTable[class] * t.correction
+}
+
+// RegisterClient configures client's token bucket: capacity tokens and a
+// priority tier. The bucket's refill rate is derived from totalRate and
+// vipShare, split evenly within client's tier, and is recomputed whenever
+// tier membership changes.
+func (t *CostTracker) RegisterClient(client ClientID, capacity float64, tier priorityTier) {
+	t.clients[client] = &clientBucket{tokens: capacity, capacity: capacity, tier: tier}
+	t.recomputeRates()
+}
+
+// SetTier reassigns client's priority tier mid-simulation and rebalances
+// every client's share of totalRate accordingly.
+func (t *CostTracker) SetTier(client ClientID, tier priorityTier) {
+	if b, ok := t.clients[client]; ok {
+		b.tier = tier
+		t.recomputeRates()
+	}
+}
+
+// recomputeRates splits totalRate between the VIP and best-effort pools
+// per vipShare, then divides each pool evenly among its current members.
+// While no VIP client is registered, nothing is reserved and the whole of
+// totalRate goes to the best-effort pool.
+func (t *CostTracker) recomputeRates() {
+	var vipCount, beCount int
+	for _, b := range t.clients {
+		if b.tier == TierVIP {
+			vipCount++
+		} else {
+			beCount++
+		}
+	}
+
+	var vipRate, beRate float64
+	if vipCount > 0 {
+		vipRate = (t.totalRate * t.vipShare) / float64(vipCount)
+	}
+	if beCount > 0 {
+		beRate = (t.totalRate * (1 - t.vipShare)) / float64(beCount)
+		if vipCount == 0 {
+			beRate = t.totalRate / float64(beCount)
+		}
+	}
+
+	for _, b := range t.clients {
+		if b.tier == TierVIP {
+			b.rate = vipRate
+		} else {
+			b.rate = beRate
+		}
+	}
+}
+
+// Admit reports whether req can be accepted right now without a client's
+// bucket going negative, and if so, debits the cost. If the bucket cannot
+// afford it, Admit returns false and the time until enough tokens will
+// have accrued, so the caller can either delay the request or drop it; if
+// the bucket's refill rate is zero it returns false and -1 instead.
+func (t *CostTracker) Admit(req *ClientReq) (bool, float64) {
+	return t.admitAt(req, engine.GetTime())
+}
+
+// admitAt is Admit with the current time passed in explicitly, so the
+// token-bucket arithmetic can be exercised by tests without a running
+// engine clock.
+func (t *CostTracker) admitAt(req *ClientReq, now float64) (bool, float64) {
+	b, ok := t.clients[req.client]
+	if !ok {
+		return true, 0
+	}
+	b.refill(now)
+	cost := t.Cost(req.class)
+	if b.tokens >= cost {
+		b.tokens -= cost
+		return true, 0
+	}
+	if b.rate <= 0 {
+		return false, -1 // starved tier (e.g. vipShare reserved it all away): will never refill
+	}
+	wait := (cost - b.tokens) / b.rate
+	return false, wait
+}
+
+// ThrottledReq marks a request terminated by flow control rather than
+// having run to completion, while preserving the original request's
+// identity (client, class, service time) for stats/SLA analysis.
+type ThrottledReq struct {
+	*ClientReq
+	reason string
+}
+
+// FlowControlledProcessor sits in front of a downstream Processor in the
+// topology and gates admission through a CostTracker. Unlike a plain
+// ReadInQueue loop, it never blocks on one client's backoff: an admittable
+// arrival is forwarded immediately regardless of what else is waiting,
+// and a throttled request is parked in a min-heap keyed by the time its
+// bucket will next afford it, driving an engine.Wait on the earliest such
+// deadline. This keeps a delayed best-effort request from holding up a
+// VIP request that arrives behind it.
+type FlowControlledProcessor struct {
+	genericProcessor
+	tracker        *CostTracker
+	dropOnThrottle bool
+	pending        *prque.PrQueue[*ClientReq, float64]
+}
+
+// NewFlowControlledProcessor returns a new *FlowControlledProcessor
+// admission-gating requests with tracker.
+func NewFlowControlledProcessor(tracker *CostTracker, dropOnThrottle bool) *FlowControlledProcessor {
+	return &FlowControlledProcessor{
+		tracker:        tracker,
+		dropOnThrottle: dropOnThrottle,
+		pending:        prque.New[*ClientReq, float64](),
+	}
+}
+
+func (p *FlowControlledProcessor) admitOrDefer(req *ClientReq) {
+	ok, wait := p.tracker.Admit(req)
+	if ok {
+		p.WriteOutQueue(req)
+		return
+	}
+	// wait < 0 means the client's tier currently has a zero refill rate
+	// (e.g. vipShare reserved it all away) and will never accrue enough
+	// tokens on its own; parking it in pending would wait forever and stall
+	// the simulation clock, so it's dropped regardless of dropOnThrottle.
+	if p.dropOnThrottle || wait < 0 {
+		p.reqDrain.TerminateReq(&ThrottledReq{ClientReq: req, reason: "throttled"})
+		return
+	}
+	p.pending.Push(req, engine.GetTime()+wait)
+}
+
+// Run is the main processor loop
+func (p *FlowControlledProcessor) Run() {
+	var d float64
+	d = -1
+	for {
+		intr, newReq := p.WaitInterruptible(d)
+		if !intr {
+			clientReq, ok := newReq.(*ClientReq)
+			if !ok {
+				p.WriteOutQueue(newReq)
+			} else {
+				p.admitOrDefer(clientReq)
+			}
+		} else if req, _, ok := p.pending.PopMin(); ok {
+			p.admitOrDefer(req)
+		}
+
+		if p.pending.Len() > 0 {
+			_, readyTime, _ := p.pending.Peek()
+			d = readyTime - engine.GetTime()
+			if d < 0 {
+				d = 0
+			}
+		} else {
+			d = -1
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package blocks
+
+import "testing"
+
+func TestFairSharingAmongBestEffortClients(t *testing.T) {
+	costTable := map[ReqClass]float64{0: 1.0}
+	tracker := NewCostTracker(costTable, 1.0, 10.0 /* totalRate */, 0.0 /* vipShare */)
+	tracker.RegisterClient(1, 5.0, TierBestEffort)
+	tracker.RegisterClient(2, 5.0, TierBestEffort)
+
+	// Two best-effort clients sharing 10 tokens/sec evenly should each get
+	// a rate of 5/sec, independent of registration order.
+	if got := tracker.clients[1].rate; got != 5.0 {
+		t.Fatalf("client 1 rate = %v, want 5.0", got)
+	}
+	if got := tracker.clients[2].rate; got != 5.0 {
+		t.Fatalf("client 2 rate = %v, want 5.0", got)
+	}
+
+	req1 := NewClientReq(1, 1, 0)
+	req2 := NewClientReq(1, 2, 0)
+	for i := 0; i < 5; i++ {
+		if ok, _ := tracker.admitAt(req1, 0); !ok {
+			t.Fatalf("client 1 request %d should have been admitted from its initial bucket", i)
+		}
+		if ok, _ := tracker.admitAt(req2, 0); !ok {
+			t.Fatalf("client 2 request %d should have been admitted from its initial bucket", i)
+		}
+	}
+}
+
+func TestVIPReservationMaintainsSLAUnderOverload(t *testing.T) {
+	costTable := map[ReqClass]float64{0: 1.0}
+	// Reserve 80% of capacity for VIP; a single VIP client gets a rate of
+	// 8/sec regardless of how many best-effort clients pile on afterwards.
+	tracker := NewCostTracker(costTable, 1.0, 10.0, 0.8)
+	tracker.RegisterClient(1, 1.0, TierVIP)
+	tracker.RegisterClient(2, 1.0, TierBestEffort)
+	tracker.RegisterClient(3, 1.0, TierBestEffort)
+	tracker.RegisterClient(4, 1.0, TierBestEffort)
+
+	if got := tracker.clients[1].rate; got != 8.0 {
+		t.Fatalf("VIP client rate = %v, want 8.0", got)
+	}
+	vipShare := 0.8
+	wantBE := (10.0 * (1 - vipShare)) / 3
+	for _, id := range []ClientID{2, 3, 4} {
+		if got := tracker.clients[id].rate; got != wantBE {
+			t.Fatalf("best-effort client %d rate = %v, want %v", id, got, wantBE)
+		}
+	}
+
+	vipReq := NewClientReq(1, 1, 0)
+	now := 0.0
+	admitted := 0
+	for i := 0; i < 20; i++ {
+		if ok, _ := tracker.admitAt(vipReq, now); ok {
+			admitted++
+		}
+		now += 0.125
+	}
+	// At 8 tokens/sec and cost 1 per request, the VIP client should sail
+	// through every one of these 2.5 seconds' worth of arrivals (one every
+	// 1/8 s) unthrottled.
+	if admitted != 20 {
+		t.Fatalf("VIP admitted = %d/20, want all admitted under its reserved share", admitted)
+	}
+
+	// A best-effort client sharing a starved pool degrades: most of a
+	// burst gets throttled instead of admitted.
+	beReq := NewClientReq(1, 2, 0)
+	now = 0.0
+	beAdmitted := 0
+	for i := 0; i < 20; i++ {
+		if ok, _ := tracker.admitAt(beReq, now); ok {
+			beAdmitted++
+		}
+		now += 0.1
+	}
+	if beAdmitted >= admitted {
+		t.Fatalf("best-effort admitted = %d, want fewer than VIP's %d under overload", beAdmitted, admitted)
+	}
+}
+
+func TestSetTierRebalancesRates(t *testing.T) {
+	costTable := map[ReqClass]float64{0: 1.0}
+	tracker := NewCostTracker(costTable, 1.0, 10.0, 0.5)
+	tracker.RegisterClient(1, 1.0, TierBestEffort)
+	tracker.RegisterClient(2, 1.0, TierBestEffort)
+
+	if got := tracker.clients[1].rate; got != 5.0 {
+		t.Fatalf("rate before promotion = %v, want 5.0 (no VIPs yet)", got)
+	}
+
+	tracker.SetTier(1, TierVIP)
+	if got := tracker.clients[1].rate; got != 5.0 {
+		t.Fatalf("sole VIP rate after promotion = %v, want 5.0 (all of the 50%% VIP share)", got)
+	}
+	if got := tracker.clients[2].rate; got != 5.0 {
+		t.Fatalf("sole best-effort rate after promotion = %v, want 5.0 (all of the 50%% best-effort share)", got)
+	}
+}
@@ -0,0 +1,160 @@
+package blocks
+
+import (
+	"math/rand"
+
+	"github.com/epfl-dcsl/schedsim/blocks/prque"
+	"github.com/epfl-dcsl/schedsim/engine"
+)
+
+// RateLimiter decides how long to delay a request that is being retried
+// after a synthesized transient failure, given how many times it has
+// already failed.
+type RateLimiter interface {
+	// NextDelay returns how long to wait before the (failures+1)-th retry.
+	NextDelay(failures int) float64
+}
+
+// ExponentialBackoff is a RateLimiter with delay = min(max, base*2^failures),
+// optionally jittered to avoid synchronized retry storms.
+type ExponentialBackoff struct {
+	base, max float64
+	jitter    float64 // fraction of the computed delay to randomize, in [0, 1]
+}
+
+// NewExponentialBackoff returns a new *ExponentialBackoff with no jitter.
+func NewExponentialBackoff(base, max float64) *ExponentialBackoff {
+	return &ExponentialBackoff{base: base, max: max}
+}
+
+// SetJitter sets the fraction of the computed delay that is randomized.
+func (b *ExponentialBackoff) SetJitter(jitter float64) {
+	b.jitter = jitter
+}
+
+// NextDelay implements RateLimiter.
+func (b *ExponentialBackoff) NextDelay(failures int) float64 {
+	delay := b.base
+	for i := 0; i < failures; i++ {
+		delay *= 2
+		if delay >= b.max {
+			delay = b.max
+			break
+		}
+	}
+	if b.jitter > 0 {
+		delay += delay * b.jitter * (rand.Float64()*2 - 1)
+		if delay < 0 {
+			delay = 0
+		}
+	}
+	return delay
+}
+
+// FailedReq marks a request a downstream Processor synthesized as a
+// transient failure, carrying its own failures count so retries of the
+// *same* FailedReq escalate correctly: AddRateLimited reuses and
+// increments this field in place rather than keying a failure count off
+// the wrapper's identity, which would change on every retry.
+type FailedReq struct {
+	Request
+	inner    engine.ReqInterface
+	failures int
+}
+
+// NewFailedReq wraps req as a failed attempt to be retried by a
+// DeferringQueue.
+func NewFailedReq(req engine.ReqInterface) *FailedReq {
+	return &FailedReq{inner: req, Request: Request{ServiceTime: req.GetServiceTime()}}
+}
+
+// deferredEntry is what actually travels through the in-queue to wake a
+// blocked DeferringQueue.Run: a payload plus the delay it should sit in
+// the ready-heap for before being forwarded downstream.
+type deferredEntry struct {
+	Request
+	payload engine.ReqInterface
+	delay   float64
+}
+
+// DeferringQueue sits in front of a Processor and lets the simulation
+// postpone work: AddAfter schedules req to be forwarded downstream once
+// delay has elapsed, and AddRateLimited re-enqueues a request that a
+// processor reported as transiently failed using a RateLimiter-driven
+// backoff. Both funnel through the actor's in-queue as a deferredEntry,
+// which interrupts Run's wait the same way a fresh arrival would; Run then
+// places the payload on a min-heap keyed by ready-time and blocks until
+// the earliest deadline, so the queue costs O(log n) per event regardless
+// of how much work is deferred.
+type DeferringQueue struct {
+	genericProcessor
+	ready   *prque.PrQueue[engine.ReqInterface, float64]
+	limiter RateLimiter
+}
+
+// NewDeferringQueue returns a new *DeferringQueue using limiter for
+// AddRateLimited backoff.
+func NewDeferringQueue(limiter RateLimiter) *DeferringQueue {
+	return &DeferringQueue{
+		ready:   prque.New[engine.ReqInterface, float64](),
+		limiter: limiter,
+	}
+}
+
+// AddAfter schedules req to be forwarded downstream once delay has
+// elapsed.
+func (q *DeferringQueue) AddAfter(req engine.ReqInterface, delay float64) {
+	q.WriteInQueue(&deferredEntry{payload: req, delay: delay})
+}
+
+// nextRetry is AddRateLimited's bookkeeping split out so it can be
+// exercised by tests without going through the engine-dependent
+// AddAfter/WriteInQueue path. If req is already a *FailedReq (i.e. this is
+// a retry of a retry), its failures count is read and incremented in place
+// so the backoff escalates across the same logical request; otherwise req
+// is wrapped as a fresh FailedReq with a failures count of zero.
+func (q *DeferringQueue) nextRetry(req engine.ReqInterface) (*FailedReq, float64) {
+	failed, ok := req.(*FailedReq)
+	if !ok {
+		failed = NewFailedReq(req)
+	}
+	delay := q.limiter.NextDelay(failed.failures)
+	failed.failures++
+	return failed, delay
+}
+
+// AddRateLimited re-enqueues req after a RateLimiter-computed backoff.
+func (q *DeferringQueue) AddRateLimited(req engine.ReqInterface) {
+	failed, delay := q.nextRetry(req)
+	q.AddAfter(failed, delay)
+}
+
+// Requeue is the entry point a downstream Processor calls when it
+// synthesizes a transient failure for req: it is resubmitted through
+// AddRateLimited, which wraps it as a FailedReq on the first failure and
+// escalates the backoff on every one after that.
+func (q *DeferringQueue) Requeue(req engine.ReqInterface) {
+	q.AddRateLimited(req)
+}
+
+// Run is the main processor loop
+func (q *DeferringQueue) Run() {
+	var d float64
+	d = -1
+	for {
+		intr, newReq := q.WaitInterruptible(d)
+		if !intr {
+			entry := newReq.(*deferredEntry)
+			q.ready.Push(entry.payload, engine.GetTime()+entry.delay)
+		} else if req, _, ok := q.ready.PopMin(); ok {
+			q.WriteOutQueue(req)
+		}
+
+		if q.ready.Len() > 0 {
+			_, readyTime, _ := q.ready.Peek()
+			d = readyTime - engine.GetTime()
+		} else {
+			d = -1
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package blocks
+
+import "testing"
+
+func TestExponentialBackoffCapsAndJitters(t *testing.T) {
+	b := NewExponentialBackoff(1.0, 16.0)
+	want := []float64{1, 2, 4, 8, 16, 16, 16}
+	for failures, w := range want {
+		if got := b.NextDelay(failures); got != w {
+			t.Fatalf("NextDelay(%d) = %v, want %v", failures, got, w)
+		}
+	}
+
+	b.SetJitter(0.5)
+	const base = 8.0 // undelayed NextDelay(3)
+	for i := 0; i < 50; i++ {
+		got := b.NextDelay(3)
+		if got < base*0.5 || got > base*1.5 {
+			t.Fatalf("jittered NextDelay(3) = %v, want within 50%% of %v", got, base)
+		}
+	}
+}
+
+// TestDeferringQueueRequeueEscalatesBackoff guards the regression fixed by
+// keying the failure count on FailedReq itself: retrying the same
+// *FailedReq that travelled downstream and back must escalate the delay
+// each time, not keep reading 0 from a freshly-allocated wrapper.
+func TestDeferringQueueRequeueEscalatesBackoff(t *testing.T) {
+	q := NewDeferringQueue(NewExponentialBackoff(1.0, 1000.0))
+
+	req := NewClientReq(5.0, 1, 0)
+	failed, d0 := q.nextRetry(req)
+	if d0 != 1.0 {
+		t.Fatalf("first retry delay = %v, want 1.0", d0)
+	}
+	if failed.failures != 1 {
+		t.Fatalf("failures after first retry = %d, want 1", failed.failures)
+	}
+
+	_, d1 := q.nextRetry(failed)
+	if d1 != 2.0 {
+		t.Fatalf("second retry delay = %v, want 2.0 (escalated)", d1)
+	}
+	if failed.failures != 2 {
+		t.Fatalf("failures after second retry = %d, want 2", failed.failures)
+	}
+
+	_, d2 := q.nextRetry(failed)
+	if d2 != 4.0 {
+		t.Fatalf("third retry delay = %v, want 4.0 (escalated again)", d2)
+	}
+}
@@ -0,0 +1,226 @@
+package blocks
+
+// NUMATopology describes a set of sockets, each holding a group of
+// Processors, plus an inter-socket latency/distance matrix (symmetric when
+// built by Uniform, taken as given by Asymmetric). It is
+// consulted by NUMAAwareLB to price cross-socket dispatch and has no
+// behaviour of its own beyond that lookup.
+type NUMATopology struct {
+	sockets   [][]Processor
+	distances [][]float64 // distances[i][j] is the cross-socket factor between socket i and j
+}
+
+// SocketCount returns the number of sockets in the topology.
+func (t *NUMATopology) SocketCount() int {
+	return len(t.sockets)
+}
+
+// Processors returns the processors attached to the given socket.
+func (t *NUMATopology) Processors(socket int) []Processor {
+	return t.sockets[socket]
+}
+
+// Factor returns the cross-socket latency multiplier from socket from to
+// socket to. Uniform sets it to 1.0 for a socket and itself; for a topology
+// built by Asymmetric it is whatever the matrix holds.
+func (t *NUMATopology) Factor(from, to int) float64 {
+	return t.distances[from][to]
+}
+
+// Uniform builds a NUMATopology of the given number of sockets, each with
+// coresPerSocket NUMAProcessors, where every cross-socket pair shares the
+// same remoteFactor and same-socket access is free (factor 1.0).
+func Uniform(sockets, coresPerSocket int, remoteFactor float64) *NUMATopology {
+	t := &NUMATopology{
+		sockets:   make([][]Processor, sockets),
+		distances: make([][]float64, sockets),
+	}
+	for s := 0; s < sockets; s++ {
+		t.distances[s] = make([]float64, sockets)
+		for d := 0; d < sockets; d++ {
+			if s == d {
+				t.distances[s][d] = 1.0
+			} else {
+				t.distances[s][d] = remoteFactor
+			}
+		}
+		procs := make([]Processor, coresPerSocket)
+		for c := 0; c < coresPerSocket; c++ {
+			procs[c] = NewNUMAProcessor(s)
+		}
+		t.sockets[s] = procs
+	}
+	return t
+}
+
+// Asymmetric builds a NUMATopology from an explicit, possibly asymmetric,
+// socket distance matrix; callers are responsible for populating each
+// socket's processors afterwards via AddProcessor.
+func Asymmetric(matrix [][]float64) *NUMATopology {
+	t := &NUMATopology{
+		sockets:   make([][]Processor, len(matrix)),
+		distances: matrix,
+	}
+	return t
+}
+
+// AddProcessor attaches p to the given socket.
+func (t *NUMATopology) AddProcessor(socket int, p *NUMAProcessor) {
+	t.sockets[socket] = append(t.sockets[socket], p)
+}
+
+// NUMAProcessor is a processor pinned to a specific socket in a
+// NUMATopology. It runs to completion like RTCProcessor; the cross-socket
+// penalty for a request is applied by NUMAAwareLB before dispatch, not by
+// the processor itself.
+type NUMAProcessor struct {
+	genericProcessor
+	socket int
+}
+
+// NewNUMAProcessor returns a new *NUMAProcessor pinned to socket.
+func NewNUMAProcessor(socket int) *NUMAProcessor {
+	return &NUMAProcessor{socket: socket}
+}
+
+// Socket returns the socket this processor belongs to.
+func (p *NUMAProcessor) Socket() int {
+	return p.socket
+}
+
+// Run is the main processor loop
+func (p *NUMAProcessor) Run() {
+	for {
+		req := p.ReadInQueue()
+		p.Wait(req.GetServiceTime() + p.ctxCost)
+		p.reqDrain.TerminateReq(req)
+	}
+}
+
+// NUMAAwareLB is a load balancer that routes a request to the
+// least-loaded idle processor for its home socket, falling back to the
+// least-loaded idle processor on a remote socket when the home socket has
+// none available. Routing to a remote socket scales the request's
+// ServiceTime by the topology's cross-socket factor to model the added
+// memory-access latency.
+type NUMAAwareLB struct {
+	genericProcessor
+	topo   *NUMATopology
+	pool   []Processor // flattened across all sockets, indexed like the LB's out-queues
+	socket []int        // socket[i] is the socket pool[i] belongs to
+}
+
+// NewNUMAAwareLB returns a new *NUMAAwareLB balancing over topo. The out-
+// queue index a request is written to mirrors the flattened (socket,
+// processor) order below, so callers must wire the LB's out-queues up to
+// topo's processors in that same order.
+func NewNUMAAwareLB(topo *NUMATopology) *NUMAAwareLB {
+	lb := &NUMAAwareLB{topo: topo}
+	for s := 0; s < topo.SocketCount(); s++ {
+		for _, p := range topo.Processors(s) {
+			lb.pool = append(lb.pool, p)
+			lb.socket = append(lb.socket, s)
+		}
+	}
+	return lb
+}
+
+// idleProcessor returns the index (into lb.pool) of the least-loaded
+// processor on socket that is actually idle (empty out-queue), or ok=false
+// if every processor on socket is currently busy.
+func (lb *NUMAAwareLB) idleProcessor(socket int) (idx int, ok bool) {
+	best := -1
+	bestLen := -1
+	for i := range lb.pool {
+		if lb.socket[i] != socket {
+			continue
+		}
+		l := lb.GetOutQueueLen(i)
+		if l > 0 {
+			continue
+		}
+		if best == -1 || l < bestLen {
+			bestLen = l
+			best = i
+		}
+	}
+	if best == -1 {
+		return 0, false
+	}
+	return best, true
+}
+
+// firstOnSocket returns the index (into lb.pool) of the first processor
+// attached to socket; used as the saturated fallback, where there is no
+// idle processor left to prefer anywhere.
+func (lb *NUMAAwareLB) firstOnSocket(socket int) int {
+	for i := range lb.pool {
+		if lb.socket[i] == socket {
+			return i
+		}
+	}
+	return 0
+}
+
+// pickTarget chooses which pool index to dispatch a request homed on home
+// to: an idle processor on home if one exists, else an idle processor on
+// whichever remote socket has one, else (every processor on every socket
+// busy) home's own first processor, so an overloaded system still queues
+// work locally rather than picking an arbitrary, possibly remote, index.
+func (lb *NUMAAwareLB) pickTarget(home int) int {
+	return lb.pickTargetUsing(home, lb.idleProcessor)
+}
+
+// pickTargetUsing is pickTarget with idleOnSocket passed in explicitly, so
+// the routing decision can be exercised by tests without a running engine.
+func (lb *NUMAAwareLB) pickTargetUsing(home int, idleOnSocket func(socket int) (int, bool)) int {
+	if idx, ok := idleOnSocket(home); ok {
+		return idx
+	}
+	for s := 0; s < lb.topo.SocketCount(); s++ {
+		if s == home {
+			continue
+		}
+		if idx, ok := idleOnSocket(s); ok {
+			return idx
+		}
+	}
+	return lb.firstOnSocket(home)
+}
+
+// Run is the main processor loop
+func (lb *NUMAAwareLB) Run() {
+	for {
+		req := lb.ReadInQueue()
+		home := 0
+		if numaReq, ok := req.(*NUMAReq); ok {
+			home = numaReq.homeSocket
+		}
+
+		chosen := lb.pickTarget(home)
+		factor := lb.topo.Factor(home, lb.socket[chosen])
+		if factor != 1.0 {
+			extra := req.GetServiceTime() * (factor - 1.0)
+			req.SubServiceTime(-extra) // scale up: SubServiceTime is the only mutator ReqInterface exposes
+		}
+		lb.WriteOutQueue(req, chosen)
+	}
+}
+
+// NUMAReq is a Request annotated with the socket its memory is homed on,
+// so a NUMAAwareLB can price remote dispatch. NUMAProcessor does not read
+// the home socket; it adds no page migration cost of its own.
+type NUMAReq struct {
+	Request
+	homeSocket int
+}
+
+// NewNUMAReq returns a new *NUMAReq homed on socket.
+func NewNUMAReq(serviceTime float64, socket int) *NUMAReq {
+	return &NUMAReq{Request: Request{ServiceTime: serviceTime}, homeSocket: socket}
+}
+
+// HomeSocket returns the socket this request's memory is homed on.
+func (r *NUMAReq) HomeSocket() int {
+	return r.homeSocket
+}
@@ -0,0 +1,51 @@
+package blocks
+
+import "testing"
+
+func TestNUMAAwareLBPrefersHomeSocketWhenIdle(t *testing.T) {
+	topo := Uniform(2, 2, 2.0)
+	lb := NewNUMAAwareLB(topo)
+
+	idx := lb.pickTargetUsing(0, func(socket int) (int, bool) {
+		if socket == 0 {
+			return 1, true
+		}
+		return 0, false
+	})
+	if idx != 1 {
+		t.Fatalf("chosen index = %d, want 1 (idle processor on home socket)", idx)
+	}
+}
+
+func TestNUMAAwareLBFallsBackToIdleRemoteSocket(t *testing.T) {
+	topo := Uniform(2, 2, 2.0)
+	lb := NewNUMAAwareLB(topo)
+
+	idx := lb.pickTargetUsing(0, func(socket int) (int, bool) {
+		if socket == 1 {
+			return 2, true
+		}
+		return 0, false
+	})
+	if lb.socket[idx] != 1 {
+		t.Fatalf("chosen socket = %d, want 1 (remote fallback)", lb.socket[idx])
+	}
+	if factor := topo.Factor(0, lb.socket[idx]); factor != 2.0 {
+		t.Fatalf("factor = %v, want 2.0", factor)
+	}
+}
+
+// TestNUMAAwareLBSaturatedFallsBackHome guards against the saturated
+// fallback silently landing on an arbitrary pool index (pool index 0 may
+// not even belong to the home socket).
+func TestNUMAAwareLBSaturatedFallsBackHome(t *testing.T) {
+	topo := Uniform(2, 2, 2.0)
+	lb := NewNUMAAwareLB(topo)
+
+	idx := lb.pickTargetUsing(1, func(socket int) (int, bool) {
+		return 0, false
+	})
+	if lb.socket[idx] != 1 {
+		t.Fatalf("saturated fallback socket = %d, want 1 (home)", lb.socket[idx])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"container/list"
 	//	"fmt"
 
+	"github.com/epfl-dcsl/schedsim/blocks/prque"
 	"github.com/epfl-dcsl/schedsim/engine"
 )
 
@@ -76,18 +77,29 @@ func (p *TSProcessor) Run() {
 }
 
 // PSProcessor is a processor sharing processor
+//
+// Requests are kept in a prque.PrQueue keyed by remaining service time, so
+// picking the next request to finish is an O(log n) heap operation instead
+// of the O(n) linear scan a plain list would need. Because processor
+// sharing reduces every in-flight request's remaining time by the same
+// amount on every event, the relative order of queued requests never
+// changes between events: updateOffset folds that shared delta into a
+// running offset instead of re-keying every element, and only the
+// currently-running request (held outside the queue in p.curr) is ever
+// re-keyed directly.
 type PSProcessor struct {
 	genericProcessor
 	workerCount int
 	count       int // how many concurrent requests
-	reqList     *list.List
-	curr        *list.Element
+	queue       *prque.PrQueue[engine.ReqInterface, float64]
+	curr        engine.ReqInterface
+	offset      float64
 	prevTime    float64
 }
 
 // NewPSProcessor returns a new *PSProcessor
 func NewPSProcessor() *PSProcessor {
-	return &PSProcessor{workerCount: 1, reqList: list.New()}
+	return &PSProcessor{workerCount: 1, queue: prque.New[engine.ReqInterface, float64]()}
 }
 
 // SetWorkerCount sets the number of workers in a processor sharing processor
@@ -95,19 +107,6 @@ func (p *PSProcessor) SetWorkerCount(count int) {
 	p.workerCount = count
 }
 
-func (p *PSProcessor) getMinService() *list.Element {
-	minS := p.reqList.Front().Value.(*Request).ServiceTime
-	minI := p.reqList.Front()
-	for e := p.reqList.Front(); e != nil; e = e.Next() {
-		val := e.Value.(*Request).ServiceTime
-		if val < minS {
-			minS = val
-			minI = e
-		}
-	}
-	return minI
-}
-
 func (p *PSProcessor) getFactor() float64 {
 	if p.workerCount > p.count {
 		return 1.0
@@ -115,14 +114,38 @@ func (p *PSProcessor) getFactor() float64 {
 	return float64(p.workerCount) / float64(p.count)
 }
 
-func (p *PSProcessor) updateServiceTimes() {
+// updateOffset advances the shared "everyone progressed by this much" delta
+// and applies it to the one request currently running (held outside the
+// queue), which is the only request whose actual remaining service time is
+// kept accurate between events.
+func (p *PSProcessor) updateOffset() float64 {
 	currTime := engine.GetTime()
 	diff := (currTime - p.prevTime) * p.getFactor()
 	p.prevTime = currTime
-	for e := p.reqList.Front(); e != nil; e = e.Next() {
-		req := e.Value.(engine.ReqInterface)
-		req.SubServiceTime(diff)
+	p.offset += diff
+	if p.curr != nil {
+		p.curr.SubServiceTime(diff)
+	}
+	return diff
+}
+
+// pushBack re-enters a request into the queue, keying it so that
+// priority-p.offset again yields its true remaining service time.
+func (p *PSProcessor) pushBack(req engine.ReqInterface) {
+	p.queue.Push(req, req.GetServiceTime()+p.offset)
+}
+
+// pickCurr pops the request with the least remaining service time out of
+// the queue and makes it the running request, reconciling its stored
+// service time with the queue's lazily-offset priority.
+func (p *PSProcessor) pickCurr() {
+	req, prio, ok := p.queue.PopMin()
+	if !ok {
+		p.curr = nil
+		return
 	}
+	req.SubServiceTime(req.GetServiceTime() - (prio - p.offset))
+	p.curr = req
 }
 
 // Run is the main processor loop
@@ -132,19 +155,24 @@ func (p *PSProcessor) Run() {
 	for {
 		intr, newReq := p.WaitInterruptible(d)
 		//update times
-		p.updateServiceTimes()
+		p.updateOffset()
 		if intr {
-			req := p.curr.Value.(engine.ReqInterface)
-			p.reqDrain.TerminateReq(req)
-			p.reqList.Remove(p.curr)
+			p.reqDrain.TerminateReq(p.curr)
+			p.curr = nil
 			p.count--
 		} else {
 			p.count++
-			p.reqList.PushBack(newReq)
+			if p.curr != nil {
+				p.pushBack(p.curr)
+				p.curr = nil
+			}
+			p.pushBack(newReq)
+		}
+		if p.curr == nil && p.count > 0 {
+			p.pickCurr()
 		}
 		if p.count > 0 {
-			p.curr = p.getMinService()
-			d = p.curr.Value.(engine.ReqInterface).GetServiceTime() / p.getFactor()
+			d = p.curr.GetServiceTime() / p.getFactor()
 		} else {
 			d = -1
 		}
@@ -314,14 +342,11 @@ func (p *LimitedPSProcessor) setLimit(limit int) {
 	p.limit = limit
 }
 
-func (p *LimitedPSProcessor) updateServiceTimesIncludingOverflow() {
-	currTime := engine.GetTime()
-	diff := (currTime - p.prevTime) * p.getFactor()
-	p.prevTime = currTime
-	for e := p.reqList.Front(); e != nil; e = e.Next() {
-		req := e.Value.(engine.ReqInterface)
-		req.SubServiceTime(diff)
-	}
+// updateOffsetIncludingOverflow behaves like PSProcessor.updateOffset but
+// additionally re-keys the requests parked in overflow, which sit outside
+// the priority queue entirely and so aren't covered by its lazy offset.
+func (p *LimitedPSProcessor) updateOffsetIncludingOverflow() {
+	diff := p.updateOffset()
 	for e := p.overflow.Front(); e != nil; e = e.Next() {
 		req := e.Value.(engine.ReqInterface)
 		req.SubServiceTime(diff)
@@ -333,32 +358,105 @@ func (p *LimitedPSProcessor) Run() {
 	d = -1
 	for {
 		intr, newReq := p.WaitInterruptible(d)
-		p.updateServiceTimesIncludingOverflow()
+		p.updateOffsetIncludingOverflow()
 
-		if (p.count == p.limit) {
+		if p.count == p.limit {
 			p.overflow.PushBack(newReq)
-		} 
+		}
 		if intr {
-			req := p.curr.Value.(engine.ReqInterface)
-			p.reqDrain.TerminateReq(req)
-			p.reqList.Remove(p.curr)
+			p.reqDrain.TerminateReq(p.curr)
+			p.curr = nil
 			p.count--
-			if(p.overflow.Len() > 0) {
+			if p.overflow.Len() > 0 {
 				front := p.overflow.Front()
-				p.reqList.PushBack(front)
+				p.pushBack(front.Value.(engine.ReqInterface))
 				p.count++
 				p.overflow.Remove(front)
 			}
 		} else {
 			p.count++
-			p.reqList.PushBack(newReq)
+			p.pushBack(newReq)
+		}
+		if p.curr == nil && p.count > 0 {
+			p.pickCurr()
 		}
 		if p.count > 0 {
-			p.curr = p.getMinService()
-			d = p.curr.Value.(engine.ReqInterface).GetServiceTime() / p.getFactor()
+			d = p.curr.GetServiceTime() / p.getFactor()
+		} else {
+			d = -1
+		}
+
+	}
+}
+
+// SRPTProcessor is a single-core shortest-remaining-processing-time
+// preemptive processor: the request with the least remaining service time
+// always runs, and an arriving request immediately preempts the running
+// one if it has less remaining service. It keeps waiting requests in a
+// prque.PrQueue like PSProcessor, but runs at most one request at a time
+// at full rate, so preemption never needs to re-key any
+// waiting request — only the running request's remaining time changes.
+type SRPTProcessor struct {
+	genericProcessor
+	queue    *prque.PrQueue[engine.ReqInterface, float64]
+	curr     engine.ReqInterface
+	prevTime float64
+}
+
+// NewSRPTProcessor returns a new *SRPTProcessor
+func NewSRPTProcessor() *SRPTProcessor {
+	return &SRPTProcessor{queue: prque.New[engine.ReqInterface, float64]()}
+}
+
+// Run is the main processor loop
+func (p *SRPTProcessor) Run() {
+	var d float64
+	d = -1
+	var ctxLeft float64 // context-switch time still owed before curr makes progress
+	for {
+		intr, newReq := p.WaitInterruptible(d)
+		currTime := engine.GetTime()
+		if p.curr != nil {
+			elapsed := currTime - p.prevTime
+			if elapsed <= ctxLeft {
+				ctxLeft -= elapsed
+			} else {
+				p.curr.SubServiceTime(elapsed - ctxLeft)
+				ctxLeft = 0
+			}
+		}
+		p.prevTime = currTime
+
+		running := p.curr
+		if intr {
+			p.reqDrain.TerminateReq(p.curr)
+			p.curr = nil
+		} else {
+			if p.curr != nil {
+				if newReq.GetServiceTime() < p.curr.GetServiceTime() {
+					p.queue.Push(p.curr, p.curr.GetServiceTime())
+					p.curr = newReq
+				} else {
+					p.queue.Push(newReq, newReq.GetServiceTime())
+				}
+			} else {
+				p.curr = newReq
+			}
+		}
+		if p.curr == nil {
+			if req, _, ok := p.queue.PopMin(); ok {
+				p.curr = req
+			}
+		}
+		if p.curr != nil {
+			if p.curr != running {
+				// ctxCost is only paid when the processor actually switches
+				// to running a (possibly new) request, not on every event.
+				ctxLeft = p.ctxCost
+			}
+			d = p.curr.GetServiceTime() + ctxLeft
 		} else {
 			d = -1
 		}
-		
 	}
 }
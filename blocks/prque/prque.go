@@ -0,0 +1,143 @@
+// Package prque implements a generic indexed priority queue (min-heap)
+// that supports cheap removal and reprioritization of arbitrary elements,
+// which plain container/heap usage does not give you for free.
+package prque
+
+import (
+	"container/heap"
+
+	"golang.org/x/exp/constraints"
+)
+
+// Handle identifies an element previously pushed onto a PrQueue. It stays
+// valid for the lifetime of the element, i.e. until it is popped or
+// explicitly removed, regardless of how the heap reshuffles internally.
+type Handle int
+
+// item is the internal heap element: a value/priority pair plus the index
+// of the item in the backing slice and the handle it was issued, both kept
+// up to date on every heap swap so a handle can be resolved back to its
+// current position in O(1).
+type item[V any, P constraints.Ordered] struct {
+	value    V
+	priority P
+	index    int
+	handle   Handle
+}
+
+// innerHeap is the container/heap.Interface implementation backing PrQueue.
+// It is kept unexported: callers only ever interact through PrQueue so the
+// index bookkeeping cannot be bypassed.
+type innerHeap[V any, P constraints.Ordered] []*item[V, P]
+
+func (h innerHeap[V, P]) Len() int { return len(h) }
+
+func (h innerHeap[V, P]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].handle < h[j].handle
+}
+
+func (h innerHeap[V, P]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *innerHeap[V, P]) Push(x any) {
+	it := x.(*item[V, P])
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *innerHeap[V, P]) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return it
+}
+
+// PrQueue is a generic indexed min-heap: the element with the smallest
+// priority is always at the front. Every pushed element gets a Handle that
+// remains valid for Peek/Remove/Update regardless of subsequent heap
+// rebalancing, making O(log n) removal and reprioritization of an
+// arbitrary in-flight element possible.
+//
+// Ties are broken by insertion order, so iteration over equal-priority
+// elements is deterministic across runs.
+type PrQueue[V any, P constraints.Ordered] struct {
+	h      innerHeap[V, P]
+	items  map[Handle]*item[V, P]
+	nextID Handle
+}
+
+// New returns an empty *PrQueue.
+func New[V any, P constraints.Ordered]() *PrQueue[V, P] {
+	return &PrQueue[V, P]{items: make(map[Handle]*item[V, P])}
+}
+
+// Len returns the number of elements currently in the queue.
+func (q *PrQueue[V, P]) Len() int {
+	return len(q.h)
+}
+
+// Push inserts value with the given priority and returns a Handle that can
+// later be used with Remove/Update.
+func (q *PrQueue[V, P]) Push(value V, priority P) Handle {
+	handle := q.nextID
+	q.nextID++
+	it := &item[V, P]{value: value, priority: priority, handle: handle}
+	heap.Push(&q.h, it)
+	q.items[handle] = it
+	return handle
+}
+
+// PopMin removes and returns the value with the smallest priority.
+func (q *PrQueue[V, P]) PopMin() (V, P, bool) {
+	var zeroV V
+	var zeroP P
+	if len(q.h) == 0 {
+		return zeroV, zeroP, false
+	}
+	it := heap.Pop(&q.h).(*item[V, P])
+	delete(q.items, it.handle)
+	return it.value, it.priority, true
+}
+
+// Peek returns the value and priority at the front of the queue without
+// removing it.
+func (q *PrQueue[V, P]) Peek() (V, P, bool) {
+	var zeroV V
+	var zeroP P
+	if len(q.h) == 0 {
+		return zeroV, zeroP, false
+	}
+	return q.h[0].value, q.h[0].priority, true
+}
+
+// Remove removes the element identified by handle and returns its value.
+func (q *PrQueue[V, P]) Remove(handle Handle) (V, bool) {
+	var zeroV V
+	it, ok := q.items[handle]
+	if !ok {
+		return zeroV, false
+	}
+	removed := heap.Remove(&q.h, it.index).(*item[V, P])
+	delete(q.items, handle)
+	return removed.value, true
+}
+
+// Update changes the priority of the element identified by handle and
+// restores the heap invariant in O(log n).
+func (q *PrQueue[V, P]) Update(handle Handle, newPriority P) bool {
+	it, ok := q.items[handle]
+	if !ok {
+		return false
+	}
+	it.priority = newPriority
+	heap.Fix(&q.h, it.index)
+	return true
+}
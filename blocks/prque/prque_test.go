@@ -0,0 +1,128 @@
+package prque
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPushPopOrder(t *testing.T) {
+	q := New[string, float64]()
+	q.Push("b", 2.0)
+	q.Push("a", 1.0)
+	q.Push("c", 3.0)
+
+	for _, want := range []string{"a", "b", "c"} {
+		got, _, ok := q.PopMin()
+		if !ok || got != want {
+			t.Fatalf("PopMin() = %q, %v, want %q", got, ok, want)
+		}
+	}
+	if _, _, ok := q.PopMin(); ok {
+		t.Fatalf("PopMin() on empty queue returned ok=true")
+	}
+}
+
+func TestHandleStaysValidAcrossUpdate(t *testing.T) {
+	q := New[int, float64]()
+	ha := q.Push(1, 5.0)
+	hb := q.Push(2, 1.0)
+	q.Push(3, 10.0)
+
+	q.Update(ha, 0.5) // a should now be the minimum
+	v, _, _ := q.Peek()
+	if v != 1 {
+		t.Fatalf("Peek() = %d, want 1 after Update", v)
+	}
+
+	if _, ok := q.Remove(hb); !ok {
+		t.Fatalf("Remove(hb) = false, want true")
+	}
+	if q.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2 after Remove", q.Len())
+	}
+}
+
+func TestDeterministicTieBreak(t *testing.T) {
+	q := New[int, float64]()
+	for i := 0; i < 100; i++ {
+		q.Push(i, 1.0)
+	}
+	for i := 0; i < 100; i++ {
+		v, _, _ := q.PopMin()
+		if v != i {
+			t.Fatalf("PopMin() = %d, want %d for equal-priority insertion order", v, i)
+		}
+	}
+}
+
+func TestManyConcurrentRequests(t *testing.T) {
+	const n = 100000
+	q := New[int, float64]()
+	handles := make([]Handle, n)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < n; i++ {
+		handles[i] = q.Push(i, rng.Float64()*float64(n))
+	}
+	if q.Len() != n {
+		t.Fatalf("Len() = %d, want %d", q.Len(), n)
+	}
+
+	// Reprioritize and remove a subset to exercise handle validity at scale.
+	for i := 0; i < n; i += 7 {
+		q.Update(handles[i], -float64(i))
+	}
+	for i := 1; i < n; i += 11 {
+		q.Remove(handles[i])
+	}
+
+	count := 0
+	var last float64
+	for q.Len() > 0 {
+		_, p, _ := q.PopMin()
+		if count > 0 && p < last {
+			t.Fatalf("priorities came out of order: %v before %v", last, p)
+		}
+		last = p
+		count++
+	}
+	if count == 0 {
+		t.Fatalf("expected remaining elements after removals, got 0")
+	}
+}
+
+func BenchmarkPrQueueVsLinearScan(b *testing.B) {
+	const n = 100000
+	q := New[int, float64]()
+	handles := make([]Handle, n)
+	for i := 0; i < n; i++ {
+		handles[i] = q.Push(i, float64(n-i))
+	}
+
+	b.Run("PrQueue/PopMin", func(b *testing.B) {
+		for i := 0; i < b.N && i < n; i++ {
+			q.PopMin()
+		}
+	})
+
+	// linearMinOf mirrors the O(n) scan PSProcessor.getMinService used to
+	// perform on every event before it was switched to PrQueue.
+	linearMinOf := func(vals []float64) int {
+		minI := 0
+		for i, v := range vals {
+			if v < vals[minI] {
+				minI = i
+			}
+		}
+		return minI
+	}
+	vals := make([]float64, n)
+	for i := range vals {
+		vals[i] = float64(n - i)
+	}
+	b.Run("LinearScan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linearMinOf(vals)
+		}
+	})
+}
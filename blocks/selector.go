@@ -0,0 +1,223 @@
+package blocks
+
+import (
+	"math/rand"
+
+	"github.com/epfl-dcsl/schedsim/engine"
+)
+
+// WorkerSelector decouples placement policy from processor implementations:
+// Ok filters which processors a request is even eligible to run on, and
+// Cmp picks the preferred one among the eligible candidates. This lets a
+// SchedulerBlock place requests across a heterogeneous pool of Processors
+// (e.g. a mix of RTCProcessor and TSProcessor) under a single selection
+// policy.
+type WorkerSelector interface {
+	// Ok reports whether p is an eligible target for req.
+	Ok(req engine.ReqInterface, p Processor) bool
+	// Cmp reports whether a is preferred over b as a target for req.
+	Cmp(req engine.ReqInterface, a, b Processor) bool
+}
+
+// blockBound is implemented by selectors (JSQ, LeastServiceTime) that need
+// to read the load probes SchedulerBlock.Register collects, so those
+// probes stay the single source of truth instead of being duplicated into
+// a second, independently-constructed map that can drift out of sync.
+type blockBound interface {
+	bindBlock(b *SchedulerBlock)
+}
+
+// resettable is implemented by selectors (Random) that need to know when a
+// new request's dispatch is starting, e.g. to reset a per-dispatch counter
+// used while scanning the pool.
+type resettable interface {
+	resetPick()
+}
+
+// SchedulerBlock owns a pool of registered Processors and, for every
+// request it reads from its in-queue, uses a WorkerSelector to filter and
+// rank the pool before forwarding the request to the chosen processor's
+// in-queue.
+type SchedulerBlock struct {
+	genericProcessor
+	selector WorkerSelector
+	pool     []Processor
+	lens     []func() int     // per-processor in-queue-length probes, indexed like pool
+	remain   []func() float64 // per-processor total-remaining-service probes, indexed like pool
+}
+
+// NewSchedulerBlock returns a new *SchedulerBlock that places requests
+// using selector.
+func NewSchedulerBlock(selector WorkerSelector) *SchedulerBlock {
+	s := &SchedulerBlock{selector: selector}
+	if bb, ok := selector.(blockBound); ok {
+		bb.bindBlock(s)
+	}
+	return s
+}
+
+// Register adds p to the pool of processors this block can place requests
+// on. lenFn reports p's current in-queue length and remainingFn reports
+// the total remaining service time queued on p; JSQ and LeastServiceTime
+// read these back through the block rather than keeping their own copies.
+func (s *SchedulerBlock) Register(p Processor, lenFn func() int, remainingFn func() float64) {
+	s.pool = append(s.pool, p)
+	s.lens = append(s.lens, lenFn)
+	s.remain = append(s.remain, remainingFn)
+}
+
+// QueueLen returns p's current in-queue length, or 0 if p was never
+// registered.
+func (s *SchedulerBlock) QueueLen(p Processor) int {
+	for i, cand := range s.pool {
+		if cand == p {
+			return s.lens[i]()
+		}
+	}
+	return 0
+}
+
+// RemainingService returns the total remaining service time queued on p,
+// or 0 if p was never registered.
+func (s *SchedulerBlock) RemainingService(p Processor) float64 {
+	for i, cand := range s.pool {
+		if cand == p {
+			return s.remain[i]()
+		}
+	}
+	return 0
+}
+
+// pick scans the pool for the selector's preferred eligible candidate for
+// req, returning (nil, -1) if none is eligible. It is split out from Run so
+// the selection logic can be exercised by tests without a running engine.
+func (s *SchedulerBlock) pick(req engine.ReqInterface) (Processor, int) {
+	if r, ok := s.selector.(resettable); ok {
+		r.resetPick()
+	}
+
+	var chosen Processor
+	chosenIdx := -1
+	for i, p := range s.pool {
+		if !s.selector.Ok(req, p) {
+			continue
+		}
+		if chosen == nil || s.selector.Cmp(req, p, chosen) {
+			chosen = p
+			chosenIdx = i
+		}
+	}
+	return chosen, chosenIdx
+}
+
+// Run is the main processor loop
+func (s *SchedulerBlock) Run() {
+	for {
+		req := s.ReadInQueue()
+
+		chosen, chosenIdx := s.pick(req)
+		if chosen == nil {
+			s.reqDrain.TerminateReq(req)
+			continue
+		}
+		s.WriteOutQueue(req, chosenIdx)
+	}
+}
+
+// JSQ (join-shortest-queue) prefers the processor with the fewest
+// in-flight requests. Every processor is eligible.
+type JSQ struct {
+	block *SchedulerBlock
+}
+
+// NewJSQ returns a new *JSQ selector.
+func NewJSQ() *JSQ {
+	return &JSQ{}
+}
+
+func (s *JSQ) bindBlock(b *SchedulerBlock) { s.block = b }
+
+// Ok implements WorkerSelector.
+func (s *JSQ) Ok(req engine.ReqInterface, p Processor) bool { return true }
+
+// Cmp implements WorkerSelector.
+func (s *JSQ) Cmp(req engine.ReqInterface, a, b Processor) bool {
+	return s.block.QueueLen(a) < s.block.QueueLen(b)
+}
+
+// LeastServiceTime prefers the processor whose queue carries the least
+// total remaining service time, which (unlike JSQ) accounts for
+// heterogeneous request sizes.
+type LeastServiceTime struct {
+	block *SchedulerBlock
+}
+
+// NewLeastServiceTime returns a new *LeastServiceTime selector.
+func NewLeastServiceTime() *LeastServiceTime {
+	return &LeastServiceTime{}
+}
+
+func (s *LeastServiceTime) bindBlock(b *SchedulerBlock) { s.block = b }
+
+// Ok implements WorkerSelector.
+func (s *LeastServiceTime) Ok(req engine.ReqInterface, p Processor) bool { return true }
+
+// Cmp implements WorkerSelector.
+func (s *LeastServiceTime) Cmp(req engine.ReqInterface, a, b Processor) bool {
+	return s.block.RemainingService(a) < s.block.RemainingService(b)
+}
+
+// Affinity only admits processors tagged with the same color as a
+// ColoredReq (any other request is admitted on every processor), and is
+// indifferent between admitted candidates (first eligible one wins).
+type Affinity struct {
+	tags map[Processor]int
+}
+
+// NewAffinity returns a new *Affinity selector using tags to map a
+// processor to its color.
+func NewAffinity(tags map[Processor]int) *Affinity {
+	return &Affinity{tags: tags}
+}
+
+// Ok implements WorkerSelector.
+func (s *Affinity) Ok(req engine.ReqInterface, p Processor) bool {
+	colorReq, ok := req.(*ColoredReq)
+	if !ok {
+		return true
+	}
+	return s.tags[p] == colorReq.color
+}
+
+// Cmp implements WorkerSelector.
+func (s *Affinity) Cmp(req engine.ReqInterface, a, b Processor) bool { return false }
+
+// Random picks an eligible processor uniformly at random using reservoir
+// sampling: seen counts how many candidates Ok has been asked about so far
+// during the current dispatch (every one is eligible; reset by
+// SchedulerBlock via resetPick before each request), and the k-th candidate displaces the
+// current pick with probability 1/k. That converges to a uniform draw over
+// however many candidates turn out to be eligible, which a fixed-odds coin
+// flip between pairs cannot: with a flat 50% replace probability, later
+// candidates in pool order are far likelier to survive to the end.
+type Random struct {
+	seen int
+}
+
+// NewRandom returns a new *Random selector.
+func NewRandom() *Random {
+	return &Random{}
+}
+
+func (s *Random) resetPick() { s.seen = 0 }
+
+// Ok implements WorkerSelector.
+func (s *Random) Ok(req engine.ReqInterface, p Processor) bool {
+	s.seen++
+	return true
+}
+
+// Cmp implements WorkerSelector.
+func (s *Random) Cmp(req engine.ReqInterface, a, b Processor) bool {
+	return rand.Intn(s.seen) == 0
+}
@@ -0,0 +1,64 @@
+package blocks
+
+import "testing"
+
+func TestSchedulerBlockJSQPicksShorterQueue(t *testing.T) {
+	block := NewSchedulerBlock(NewJSQ())
+	a := &RTCProcessor{}
+	b := &RTCProcessor{}
+	lenA, lenB := 3, 1
+	block.Register(a, func() int { return lenA }, func() float64 { return 0 })
+	block.Register(b, func() int { return lenB }, func() float64 { return 0 })
+
+	chosen, idx := block.pick(nil)
+	if chosen != b || idx != 1 {
+		t.Fatalf("JSQ chose index %d, want 1 (shorter queue)", idx)
+	}
+}
+
+func TestSchedulerBlockLeastServiceTimePicksLeastRemaining(t *testing.T) {
+	block := NewSchedulerBlock(NewLeastServiceTime())
+	a := &RTCProcessor{}
+	b := &RTCProcessor{}
+	block.Register(a, func() int { return 0 }, func() float64 { return 10.0 })
+	block.Register(b, func() int { return 0 }, func() float64 { return 2.0 })
+
+	chosen, idx := block.pick(nil)
+	if chosen != b || idx != 1 {
+		t.Fatalf("LeastServiceTime chose index %d, want 1 (least remaining service)", idx)
+	}
+}
+
+func TestAffinityAdmitsUntaggedRequestsEverywhere(t *testing.T) {
+	a := &RTCProcessor{}
+	sel := NewAffinity(map[Processor]int{a: 1})
+	if !sel.Ok(nil, a) {
+		t.Fatalf("Affinity.Ok(nil, ...) = false, want true for a request with no color tag")
+	}
+}
+
+// TestRandomPicksUniformly guards against a coinflip-style Cmp, which
+// biases strongly towards whichever candidate Ok sees last instead of
+// drawing uniformly among all eligible candidates.
+func TestRandomPicksUniformly(t *testing.T) {
+	block := NewSchedulerBlock(NewRandom())
+	const n = 4
+	for i := 0; i < n; i++ {
+		block.Register(&RTCProcessor{}, func() int { return 0 }, func() float64 { return 0 })
+	}
+
+	const trials = 20000
+	counts := make([]int, n)
+	for i := 0; i < trials; i++ {
+		_, idx := block.pick(nil)
+		counts[idx]++
+	}
+
+	want := float64(trials) / float64(n)
+	for i, c := range counts {
+		got := float64(c)
+		if got < want*0.8 || got > want*1.2 {
+			t.Fatalf("index %d chosen %d/%d times, want close to uniform %v", i, c, trials, want)
+		}
+	}
+}